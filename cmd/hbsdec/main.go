@@ -0,0 +1,323 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"golang.org/x/crypto/ssh/terminal" //nolint:gci
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/nlippke/hbsdecipher-go/pkg/hbs"
+)
+
+const application string = "hbsdec"
+const ver string = "0.1.0"
+const errparameters int = 1
+const errordecipher int = 2
+
+var password *string
+var verbose *bool
+var outDirectory *string
+var encipher *bool
+var compress *bool
+var workers *int
+var keyfiles keyfileFlag
+var keyfilesOrderIndependent *bool
+var inDirectory string
+var failures int
+
+// keyfileFlag collects repeated -kf occurrences into a slice, the way
+// flag.Value is conventionally used for repeatable flags in this codebase.
+type keyfileFlag []string
+
+func (k *keyfileFlag) String() string {
+	return strings.Join(*k, ",")
+}
+
+func (k *keyfileFlag) Set(value string) error {
+	*k = append(*k, value)
+
+	return nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Printf("%s v%s (options) file1 directory2 ...\nOptions:\n", application, ver)
+		flag.PrintDefaults()
+	}
+
+	password = flag.String("p", "", "password for decryption")
+	recursive := flag.Bool("r", false, "traverse directories recursively")
+	verbose = flag.Bool("v", false, "verbose")
+	outDirectory = flag.String("o", "", "output directory (optional)")
+	encipher = flag.Bool("e", false, "encipher file(s) instead of deciphering")
+	compress = flag.Bool("c", false, "flate-compress plaintext before enciphering (only with -e)")
+	workers = flag.Int("w", 1, "number of workers for parallel HBS v2 decryption")
+	flag.Var(&keyfiles, "kf", "keyfile to combine with the password (repeatable)")
+	keyfilesOrderIndependent = flag.Bool("kfo", false, "combine keyfiles order-independently")
+	flag.Parse()
+	filesOrDirectories := flag.Args()
+
+	if len(filesOrDirectories) == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "need at least one file or directory")
+
+		flag.Usage()
+
+		os.Exit(errparameters)
+	}
+
+	if len(*password) == 0 && len(keyfiles) == 0 {
+		p, err := readPassword()
+		if err != nil || len(p) == 0 {
+			_, _ = fmt.Fprintf(os.Stderr, "\n\nMissing password!!!")
+
+			os.Exit(errparameters)
+		}
+
+		password = &p
+	}
+
+	if len(*outDirectory) > 0 && *outDirectory != "-" {
+		if *verbose {
+			fmt.Printf("Start deciphering into %s\n", *outDirectory)
+		}
+
+		if err := os.MkdirAll(*outDirectory, os.ModePerm); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%v", err)
+			os.Exit(errparameters)
+		}
+	}
+
+	failures = 0
+
+	for _, fileOrDirectory := range filesOrDirectories {
+		if fileOrDirectory == "-" {
+			if err := processStdin(); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "-: %v\n", err)
+
+				if errors.Is(err, hbs.ErrDecipher) {
+					failures++
+				}
+			}
+
+			continue
+		}
+
+		f, err := os.Stat(fileOrDirectory)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+			failures++
+
+			continue
+		}
+		if f.IsDir() {
+			inDirectory = fileOrDirectory
+		} else {
+			inDirectory = filepath.Dir(fileOrDirectory)
+		}
+
+		if *recursive {
+			if err := filepath.Walk(fileOrDirectory, processFileOrDirectory); err != nil {
+				failures++
+			}
+		} else {
+			entries, err := ReadDir(fileOrDirectory)
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "%v", err)
+			}
+			for _, entry := range entries {
+				_ = processFileOrDirectory(filepath.Clean(filepath.Dir(fileOrDirectory)+"/"+entry.Name()),
+					entry, nil)
+			}
+		}
+	}
+
+	if failures > 0 {
+		os.Exit(errordecipher)
+	}
+
+	os.Exit(0)
+}
+
+func readPassword() (string, error) {
+	fmt.Print("Enter Password: ")
+
+	bytePassword, err := terminal.ReadPassword(syscall.Stdin)
+	if err != nil {
+		return "", err
+	}
+
+	password := string(bytePassword)
+
+	return strings.TrimSpace(password), nil
+}
+
+func processFileOrDirectory(path string, info os.FileInfo, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return nil
+	}
+
+	if *outDirectory == "-" {
+		if *encipher {
+			err = fmt.Errorf("%w: enciphering to stdout is not supported", hbs.ErrDecipher)
+		} else {
+			var cipherFile *os.File
+
+			cipherFile, err = os.Open(path)
+			if err == nil {
+				err = hbs.DecipherStream(cipherFile, os.Stdout, &hbs.DecipherParam{
+					Password:                 *password,
+					Verbose:                  *verbose,
+					Workers:                  *workers,
+					Keyfiles:                 keyfiles,
+					KeyfilesOrderIndependent: *keyfilesOrderIndependent,
+				})
+
+				_ = cipherFile.Close()
+			}
+		}
+
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+
+			if errors.Is(err, hbs.ErrDecipher) {
+				failures++
+			}
+		}
+
+		return nil
+	}
+
+	var targetFileName string
+
+	prefix := "plain_"
+	if *encipher {
+		prefix = "cipher_"
+	}
+
+	if len(*outDirectory) != 0 {
+		if strings.HasPrefix(path, inDirectory) {
+			targetFileName = filepath.Clean(*outDirectory + "/" + path[len(inDirectory):])
+		} else {
+			targetFileName = filepath.Clean(*outDirectory + "/" + info.Name())
+		}
+	} else {
+		targetFileName = filepath.Clean(filepath.Dir(path) + "/" + prefix + info.Name())
+	}
+
+	if *encipher {
+		err = hbs.EncipherFile(&hbs.EncipherParam{
+			PlainFileName:            path,
+			CipheredFileName:         targetFileName,
+			Password:                 *password,
+			Compress:                 *compress,
+			Verbose:                  *verbose,
+			Keyfiles:                 keyfiles,
+			KeyfilesOrderIndependent: *keyfilesOrderIndependent,
+		})
+	} else {
+		if strings.HasSuffix(targetFileName, hbs.QnapBz2Extension) {
+			targetFileName = targetFileName[0:strings.LastIndex(targetFileName, hbs.QnapBz2Extension)]
+		}
+
+		err = hbs.DecipherFile(&hbs.DecipherParam{
+			CipheredFileName:         path,
+			PlainFileName:            targetFileName,
+			Password:                 *password,
+			Verbose:                  *verbose,
+			Workers:                  *workers,
+			Keyfiles:                 keyfiles,
+			KeyfilesOrderIndependent: *keyfilesOrderIndependent,
+		})
+	}
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+
+		if errors.Is(err, hbs.ErrDecipher) {
+			failures++
+		}
+	}
+
+	return nil
+}
+
+// processStdin deciphers the ciphered blob read from stdin, writing the
+// plaintext to stdout when outDirectory is "-" or to a plain_stdin file
+// otherwise. Enciphering from stdin isn't supported: NewWriter needs the
+// plaintext size up front, which an unbounded pipe can't provide.
+func processStdin() error {
+	if *encipher {
+		return fmt.Errorf("%w: enciphering from stdin is not supported", hbs.ErrDecipher)
+	}
+
+	var out io.Writer
+
+	if *outDirectory == "-" {
+		out = os.Stdout
+	} else {
+		targetFileName := "plain_stdin"
+		if len(*outDirectory) != 0 {
+			targetFileName = filepath.Clean(*outDirectory + "/" + targetFileName)
+		}
+
+		outFile, err := os.Create(targetFileName)
+		if err != nil {
+			return fmt.Errorf("%w: invalid target file: %v", hbs.ErrDecipher, err)
+		}
+
+		defer func() {
+			_ = outFile.Close()
+		}()
+
+		out = outFile
+	}
+
+	return hbs.DecipherStream(os.Stdin, out, &hbs.DecipherParam{
+		Password:                 *password,
+		Verbose:                  *verbose,
+		Workers:                  *workers,
+		Keyfiles:                 keyfiles,
+		KeyfilesOrderIndependent: *keyfilesOrderIndependent,
+	})
+}
+
+// ReadDir reads the directory named by dirname and returns
+// a list of directory entries sorted by filename.
+// If argument is a file instead of a directory it's info is returned.
+func ReadDir(dirname string) ([]os.FileInfo, error) {
+	f, err := os.Open(dirname)
+	if err != nil {
+		return nil, err
+	}
+
+	ftype, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ftype.IsDir() {
+		return []os.FileInfo{ftype}, nil
+	}
+
+	list, err := f.Readdir(-1)
+
+	_ = f.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+
+	return list, nil
+}
@@ -0,0 +1,402 @@
+package hbs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/md5" //nolint:gosec
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nlippke/hbsdecipher-go/pkg/openssl"
+)
+
+const testpassword string = "test123"
+const plainText string = "This is plain text."
+const debug bool = false
+
+func TestMain(m *testing.M) {
+	if err := os.Mkdir("out", os.ModePerm); err != nil {
+		panic(err)
+	}
+
+	result := m.Run()
+	_ = os.RemoveAll("out")
+	os.Exit(result)
+}
+
+func TestV2NotCompressed(t *testing.T) {
+	err := DecipherFile(&DecipherParam{
+		CipheredFileName: "examples/qnap_v2_not_compressed.txt",
+		PlainFileName:    "out/qnap_v2_not_compressed.txt",
+		Password:         testpassword,
+		Verbose:          debug,
+	})
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	result, _ := readFile("out/qnap_v2_not_compressed.txt")
+
+	if strings.Compare(*result, plainText) != 0 {
+		t.Errorf("Expected '%s' but got '%s'", plainText, *result)
+	}
+}
+
+func TestV2Compressed(t *testing.T) {
+	err := DecipherFile(&DecipherParam{
+		CipheredFileName: "examples/qnap_v2_compressed.txt",
+		PlainFileName:    "out/qnap_v2_compressed.txt",
+		Password:         testpassword,
+		Verbose:          debug,
+	})
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	result, _ := readFile("out/qnap_v2_compressed.txt")
+
+	if !strings.HasPrefix(*result, "0123456789") {
+		t.Errorf("Expected '0123456789...' but got '%s'", (*result)[0:10])
+	}
+}
+
+func TestV1NotCompressed(t *testing.T) {
+	err := DecipherFile(&DecipherParam{
+		CipheredFileName: "examples/qnap_v1_not_compressed.txt",
+		PlainFileName:    "out/qnap_v1_not_compressed.txt",
+		Password:         testpassword,
+		Verbose:          debug,
+	})
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	result, _ := readFile("out/qnap_v1_not_compressed.txt")
+
+	if strings.Compare(*result, plainText) != 0 {
+		t.Errorf("Expected '%s' but got '%s'", plainText, *result)
+	}
+}
+
+func TestV1Compressed(t *testing.T) {
+	err := DecipherFile(&DecipherParam{
+		CipheredFileName: "examples/qnap_v1_compressed.txt",
+		PlainFileName:    "out/qnap_v1_compressed.txt",
+		Password:         testpassword,
+		Verbose:          debug,
+	})
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	result, _ := readFile("out/qnap_v1_compressed.txt")
+
+	if !strings.HasPrefix(*result, "0123456789") {
+		t.Errorf("Expected '0123456789...' but got '%s'", (*result)[0:10])
+	}
+}
+
+func TestOpenSSLNotCompressed(t *testing.T) {
+	err := DecipherFile(&DecipherParam{
+		CipheredFileName: "examples/openssl.txt",
+		PlainFileName:    "out/openssl.txt",
+		Password:         testpassword,
+		Verbose:          debug,
+	})
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	result, _ := readFile("out/openssl.txt")
+
+	if strings.Compare(*result, plainText) != 0 {
+		t.Errorf("Expected '%s' but got '%s'", plainText, *result)
+	}
+}
+
+func TestOpenSSLCompressed(t *testing.T) {
+	err := DecipherFile(&DecipherParam{
+		CipheredFileName: "examples/openssl.txt.qnap.bz2",
+		PlainFileName:    "out/openssl.txt",
+		Password:         testpassword,
+		Verbose:          debug,
+	})
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	result, _ := readFile("out/openssl.txt")
+
+	if !strings.HasPrefix(*result, "0123456789") {
+		t.Errorf("Expected '0123456789...' but got '%s'", (*result)[0:10])
+	}
+}
+
+func TestEncipherDecipherRoundTrip(t *testing.T) {
+	if err := os.WriteFile("out/plain.txt", []byte(plainText), os.ModePerm); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	err := EncipherFile(&EncipherParam{
+		PlainFileName:    "out/plain.txt",
+		CipheredFileName: "out/roundtrip.ciphered",
+		Password:         testpassword,
+		Verbose:          debug,
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	err = DecipherFile(&DecipherParam{
+		CipheredFileName: "out/roundtrip.ciphered",
+		PlainFileName:    "out/roundtrip.plain",
+		Password:         testpassword,
+		Verbose:          debug,
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	result, _ := readFile("out/roundtrip.plain")
+	if strings.Compare(*result, plainText) != 0 {
+		t.Errorf("Expected '%s' but got '%s'", plainText, *result)
+	}
+}
+
+func TestEncipherDecipherRoundTripCompressed(t *testing.T) {
+	tenK := strings.Repeat("0123456789", 1000)
+
+	if err := os.WriteFile("out/plain_compressed.txt", []byte(tenK), os.ModePerm); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	err := EncipherFile(&EncipherParam{
+		PlainFileName:    "out/plain_compressed.txt",
+		CipheredFileName: "out/roundtrip_compressed.ciphered",
+		Password:         testpassword,
+		Compress:         true,
+		Verbose:          debug,
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	err = DecipherFile(&DecipherParam{
+		CipheredFileName: "out/roundtrip_compressed.ciphered",
+		PlainFileName:    "out/roundtrip_compressed.plain",
+		Password:         testpassword,
+		Verbose:          debug,
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	result, _ := readFile("out/roundtrip_compressed.plain")
+	if strings.Compare(*result, tenK) != 0 {
+		t.Errorf("round-tripped compressed content did not match")
+	}
+}
+
+func TestV2ParallelMatchesSingleThreaded(t *testing.T) {
+	plain := strings.Repeat("0123456789abcdef", 10000) + "x" // larger than one chunk
+
+	var ciphertext bytes.Buffer
+
+	w, err := NewWriter(&ciphertext, testpassword, EncOpts{Size: uint64(len(plain))})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if _, err := w.Write([]byte(plain)); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	r, err := NewReaderOpts(bytes.NewReader(ciphertext.Bytes()), testpassword, ReaderOpts{Workers: 4, ChunkSize: 256})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	_ = r.Close()
+
+	if string(got) != plain {
+		t.Errorf("parallel decipher did not match original plaintext")
+	}
+}
+
+// chunkBoundaryLengths are plaintext sizes whose PKCS5-padded ciphertext
+// lands exactly on a blockReader chunk boundary (BlockSize*BlockSize == 256
+// bytes): 240+16 padding == 256, 496+16 == 512, 752+16 == 768.
+var chunkBoundaryLengths = []int{240, 496, 752}
+
+func TestV2RoundTripChunkBoundary(t *testing.T) {
+	for _, size := range chunkBoundaryLengths {
+		t.Run(fmt.Sprintf("size=%d", size), func(t *testing.T) {
+			plain := strings.Repeat("x", size)
+
+			var ciphertext bytes.Buffer
+
+			w, err := NewWriter(&ciphertext, testpassword, EncOpts{Size: uint64(size)})
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			if _, err := w.Write([]byte(plain)); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			r, err := NewReader(bytes.NewReader(ciphertext.Bytes()), testpassword)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			_ = r.Close()
+
+			if string(got) != plain {
+				t.Errorf("expected %d bytes of plaintext, got %d", len(plain), len(got))
+			}
+		})
+	}
+}
+
+// buildV1Ciphertext assembles a QNAP cipher type 1 blob around plain,
+// byte-for-byte what a real QNAP HBS agent would produce, for tests that
+// need a v1 fixture EncipherFile can't generate (it only writes v2).
+func buildV1Ciphertext(t *testing.T, password string, plain []byte) []byte {
+	t.Helper()
+
+	salt := []byte("v1salt!!")
+
+	keyAndIV := openssl.EVPBytesToKey(AesKeyStrength/8, 16, md5.New(), salt, []byte(password), Iterations) //nolint:gosec
+
+	block, err := aes.NewCipher(keyAndIV[IndexKey])
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var body bytes.Buffer
+
+	cbc := newCBCWriter(&body, block, keyAndIV[IndexIv])
+
+	if _, err := cbc.Write(plain); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := cbc.Close(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	var blob bytes.Buffer
+	blob.Write(QNAPFilePrefixV1Bytes)
+	blob.Write(salt)
+	blob.WriteByte(0)
+	blob.Write(body.Bytes())
+
+	return blob.Bytes()
+}
+
+func TestV1RoundTripChunkBoundary(t *testing.T) {
+	for _, size := range chunkBoundaryLengths {
+		t.Run(fmt.Sprintf("size=%d", size), func(t *testing.T) {
+			plain := strings.Repeat("x", size)
+
+			ciphertext := buildV1Ciphertext(t, testpassword, []byte(plain))
+
+			r, err := NewReader(bytes.NewReader(ciphertext), testpassword)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			_ = r.Close()
+
+			if string(got) != plain {
+				t.Errorf("expected %d bytes of plaintext, got %d", len(plain), len(got))
+			}
+		})
+	}
+}
+
+func TestDecipherStreamPipe(t *testing.T) {
+	var ciphertext bytes.Buffer
+
+	w, err := NewWriter(&ciphertext, testpassword, EncOpts{Size: uint64(len(plainText))})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if _, err := w.Write([]byte(plainText)); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+
+	go func() {
+		_, _ = pipeWriter.Write(ciphertext.Bytes())
+		_ = pipeWriter.Close()
+	}()
+
+	var plain bytes.Buffer
+
+	err = DecipherStream(pipeReader, &plain, &DecipherParam{Password: testpassword, Verbose: debug})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if plain.String() != plainText {
+		t.Errorf("Expected '%s' but got '%s'", plainText, plain.String())
+	}
+}
+
+func readFile(name string) (*string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(f)
+
+	in := make([]byte, 1024)
+	buf := bytes.NewBuffer(make([]byte, 0))
+
+	for {
+		n, _ := r.Read(in)
+		if n > 0 {
+			buf.Write(in[0:n])
+		}
+		if n < len(in) {
+			break
+		}
+	}
+
+	result := buf.String()
+
+	return &result, nil
+}
@@ -0,0 +1,50 @@
+package hbs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// DeriveKeyfilePassword combines password with the SHA3-256 digests of one
+// or more keyfiles, the way Picocrypt does: each keyfile's contents is
+// hashed, the digests are XORed together in order, and the result is
+// appended to password. When orderIndependent is true the digests are
+// sorted before combining, so the same set of keyfiles yields the same
+// effective password regardless of the order they were supplied in.
+//
+// With no keyfiles, password is returned unchanged.
+func DeriveKeyfilePassword(password string, keyfiles []string, orderIndependent bool) (string, error) {
+	if len(keyfiles) == 0 {
+		return password, nil
+	}
+
+	digests := make([][]byte, len(keyfiles))
+
+	for i, path := range keyfiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid keyfile %s: %v", ErrDecipher, path, err)
+		}
+
+		sum := sha3.Sum256(data)
+		digests[i] = sum[:]
+	}
+
+	if orderIndependent {
+		sort.Slice(digests, func(i, j int) bool { return bytes.Compare(digests[i], digests[j]) < 0 })
+	}
+
+	combined := make([]byte, len(digests[0]))
+
+	for _, digest := range digests {
+		for i := range combined {
+			combined[i] ^= digest[i]
+		}
+	}
+
+	return password + string(combined), nil
+}
@@ -0,0 +1,84 @@
+package hbs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeKeyfile(t *testing.T, dir, name string, content []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, content, os.ModePerm); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return path
+}
+
+func TestDeriveKeyfilePasswordNoKeyfiles(t *testing.T) {
+	got, err := DeriveKeyfilePassword(testpassword, nil, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if got != testpassword {
+		t.Errorf("expected password to be unchanged, got %q", got)
+	}
+}
+
+func TestDeriveKeyfilePasswordOrderIndependent(t *testing.T) {
+	dir := t.TempDir()
+	kf1 := writeKeyfile(t, dir, "a.key", []byte("keyfile a"))
+	kf2 := writeKeyfile(t, dir, "b.key", []byte("keyfile b"))
+
+	forward, err := DeriveKeyfilePassword(testpassword, []string{kf1, kf2}, true)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	backward, err := DeriveKeyfilePassword(testpassword, []string{kf2, kf1}, true)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if forward != backward {
+		t.Errorf("expected order-independent combination to match regardless of keyfile order")
+	}
+}
+
+func TestEncipherDecipherWithKeyfile(t *testing.T) {
+	dir := t.TempDir()
+	kf := writeKeyfile(t, dir, "secret.key", []byte("super secret keyfile content"))
+
+	if err := os.WriteFile("out/plain_kf.txt", []byte(plainText), os.ModePerm); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := EncipherFile(&EncipherParam{
+		PlainFileName:    "out/plain_kf.txt",
+		CipheredFileName: "out/roundtrip_kf.ciphered",
+		Password:         testpassword,
+		Keyfiles:         []string{kf},
+		Verbose:          debug,
+	}); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := DecipherFile(&DecipherParam{
+		CipheredFileName:         "out/roundtrip_kf.ciphered",
+		PlainFileName:            "out/roundtrip_kf.plain",
+		Password:                 testpassword,
+		Keyfiles:                 []string{kf},
+		KeyfilesOrderIndependent: false,
+		Verbose:                  debug,
+	}); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	result, _ := readFile("out/roundtrip_kf.plain")
+	if *result != plainText {
+		t.Errorf("Expected '%s' but got '%s'", plainText, *result)
+	}
+}
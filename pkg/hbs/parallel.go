@@ -0,0 +1,230 @@
+package hbs
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DefaultChunkSize is the ciphertext chunk size (in bytes) a
+// parallelBlockReader worker decrypts at a time when ReaderOpts.ChunkSize
+// is left at its zero value: 64 KiB, i.e. 4096 AES blocks.
+const DefaultChunkSize int = 64 * 1024
+
+// chunkJob is one unit of work handed to a decrypt worker: a ciphertext
+// chunk, the CBC IV it must be decrypted with (the previous chunk's last
+// ciphertext block), and whether it is the final chunk of the body and
+// therefore needs its PKCS5 padding trimmed.
+type chunkJob struct {
+	ciphertext []byte
+	iv         []byte
+	final      bool
+	result     chan chunkResult
+}
+
+type chunkResult struct {
+	plaintext []byte
+	err       error
+}
+
+// parallelBlockReader decrypts an AES/CBC/PKCS5Padding ciphertext stream
+// using a pool of worker goroutines, similar in spirit to rclone's crypt
+// block model: a single dispatcher goroutine reads the ciphertext
+// sequentially (so it alone tracks the chaining IVs) and fans each chunk
+// out to a worker, while a ring buffer of per-chunk result channels lets
+// Read reassemble the plaintext in order regardless of which worker
+// finishes first.
+type parallelBlockReader struct {
+	queue     chan chan chunkResult
+	errc      chan error
+	cancel    chan struct{}
+	closeOnce sync.Once
+	current   []byte
+	pos       int
+	done      bool
+}
+
+func newParallelBlockReader(src io.Reader, block cipher.Block, iv []byte, workers, chunkSize int) *parallelBlockReader {
+	if workers < 1 {
+		workers = 1
+	}
+
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	chunkSize -= chunkSize % BlockSize
+	if chunkSize == 0 {
+		chunkSize = BlockSize
+	}
+
+	jobs := make(chan chunkJob, workers)
+	queue := make(chan chan chunkResult, workers*2)
+	errc := make(chan error, 1)
+	cancel := make(chan struct{})
+
+	for i := 0; i < workers; i++ {
+		go decryptWorker(block, jobs)
+	}
+
+	go dispatchChunks(src, iv, chunkSize, jobs, queue, errc, cancel)
+
+	return &parallelBlockReader{queue: queue, errc: errc, cancel: cancel}
+}
+
+// Close stops the dispatcher goroutine if it is still reading src, so that a
+// caller abandoning the Reader before EOF (an error mid-copy, or simply not
+// draining it) doesn't leave the dispatcher parked forever on a full queue
+// and its workers parked forever on an empty jobs channel.
+func (p *parallelBlockReader) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.cancel)
+	})
+
+	return nil
+}
+
+func decryptWorker(block cipher.Block, jobs chan chunkJob) {
+	for j := range jobs {
+		mode := cipher.NewCBCDecrypter(block, j.iv)
+		decrypted := make([]byte, len(j.ciphertext))
+		mode.CryptBlocks(decrypted, j.ciphertext)
+
+		if j.final {
+			trimmed, err := pkcs5Trim(decrypted, block.BlockSize())
+			if err != nil {
+				j.result <- chunkResult{err: err}
+
+				continue
+			}
+
+			decrypted = trimmed
+		}
+
+		j.result <- chunkResult{plaintext: decrypted}
+	}
+}
+
+// dispatchChunks reads chunkSize-byte chunks from src in order, tracking
+// the CBC IV chain itself, and hands each off to the worker pool via jobs.
+// It holds back one chunk at a time so that, once src is exhausted, it
+// knows for certain which chunk was the last one and can flag it final.
+//
+// submit's sends are guarded by cancel, which parallelBlockReader.Close
+// closes: without it, a consumer that stops draining Read before EOF (an
+// error mid-copy, or simply abandoning the Reader) would leave the
+// dispatcher parked forever on a full queue, and the worker pool parked
+// forever on an empty jobs channel.
+func dispatchChunks(src io.Reader, iv []byte, chunkSize int, jobs chan chunkJob, queue chan chan chunkResult, errc chan error, cancel chan struct{}) {
+	defer close(jobs)
+	defer close(queue)
+
+	prevIV := iv
+
+	var pending []byte
+
+	submit := func(ciphertext []byte, final bool) bool {
+		result := make(chan chunkResult, 1)
+
+		select {
+		case jobs <- chunkJob{ciphertext: ciphertext, iv: prevIV, final: final, result: result}:
+		case <-cancel:
+			return false
+		}
+
+		select {
+		case queue <- result:
+		case <-cancel:
+			return false
+		}
+
+		prevIV = ciphertext[len(ciphertext)-BlockSize:]
+
+		return true
+	}
+
+	for {
+		select {
+		case <-cancel:
+			return
+		default:
+		}
+
+		chunk := make([]byte, chunkSize)
+
+		n, err := io.ReadFull(src, chunk)
+
+		switch {
+		case err == nil:
+			if pending != nil {
+				if !submit(pending, false) {
+					return
+				}
+			}
+
+			pending = chunk[:n]
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			if n > 0 {
+				if n%BlockSize != 0 {
+					errc <- fmt.Errorf("%w: invalid blocksize", ErrDecipher)
+
+					return
+				}
+
+				if pending != nil {
+					if !submit(pending, false) {
+						return
+					}
+				}
+
+				pending = chunk[:n]
+			}
+
+			if pending != nil {
+				submit(pending, true)
+			}
+
+			return
+		default:
+			errc <- fmt.Errorf("%w: %v", ErrDecipher, err)
+
+			return
+		}
+	}
+}
+
+func (p *parallelBlockReader) Read(b []byte) (int, error) {
+	for p.pos >= len(p.current) {
+		if p.done {
+			return 0, io.EOF
+		}
+
+		resultCh, ok := <-p.queue
+		if !ok {
+			p.done = true
+
+			select {
+			case err := <-p.errc:
+				return 0, err
+			default:
+				return 0, io.EOF
+			}
+		}
+
+		result := <-resultCh
+		if result.err != nil {
+			p.done = true
+
+			return 0, result.err
+		}
+
+		p.current, p.pos = result.plaintext, 0
+	}
+
+	n := copy(b, p.current[p.pos:])
+	p.pos += n
+
+	return n, nil
+}
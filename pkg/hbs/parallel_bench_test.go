@@ -0,0 +1,66 @@
+package hbs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// buildBenchCiphertext enciphers size random bytes in memory, returning the
+// resulting cipher type 2 blob for BenchmarkDecipherV2 to decipher.
+func buildBenchCiphertext(b *testing.B, size int) []byte {
+	b.Helper()
+
+	plain := make([]byte, size)
+	if _, err := rand.Read(plain); err != nil {
+		b.Fatalf("%v", err)
+	}
+
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, testpassword, EncOpts{Size: uint64(size)})
+	if err != nil {
+		b.Fatalf("%v", err)
+	}
+
+	if _, err := w.Write(plain); err != nil {
+		b.Fatalf("%v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		b.Fatalf("%v", err)
+	}
+
+	return buf.Bytes()
+}
+
+// BenchmarkDecipherV2 demonstrates the speedup the parallel block-decryption
+// pipeline gives on a ~1 GiB cipher type 2 body: run with
+// `go test -bench BenchmarkDecipherV2 -benchtime 3x ./pkg/hbs`.
+func BenchmarkDecipherV2(b *testing.B) {
+	const size = 1 << 30 // ~1 GiB
+
+	ciphertext := buildBenchCiphertext(b, size)
+
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				r, err := NewReaderOpts(bytes.NewReader(ciphertext), testpassword, ReaderOpts{Workers: workers})
+				if err != nil {
+					b.Fatalf("%v", err)
+				}
+
+				if _, err := io.Copy(io.Discard, r); err != nil {
+					b.Fatalf("%v", err)
+				}
+
+				_ = r.Close()
+			}
+		})
+	}
+}
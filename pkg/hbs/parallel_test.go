@@ -0,0 +1,72 @@
+package hbs
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParallelBlockReaderCloseStopsDispatcher guards against a goroutine
+// leak: if a caller abandons a parallelBlockReader before EOF (an error
+// mid-copy, or simply not draining it), Close must unblock the dispatcher
+// goroutine and let the worker pool exit, rather than leaving them parked
+// forever on a full queue and an empty jobs channel respectively.
+func TestParallelBlockReaderCloseStopsDispatcher(t *testing.T) {
+	plain := strings.Repeat("0123456789abcdef", 100000) // several chunks at ChunkSize 256
+
+	var ciphertext bytes.Buffer
+
+	w, err := NewWriter(&ciphertext, testpassword, EncOpts{Size: uint64(len(plain))})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if _, err := w.Write([]byte(plain)); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	runtime.GC()
+
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		r, err := NewReaderOpts(bytes.NewReader(ciphertext.Bytes()), testpassword, ReaderOpts{Workers: 4, ChunkSize: 256})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		buf := make([]byte, 16)
+		if _, err := r.Read(buf); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if err := r.Close(); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+
+	var after int
+
+	for {
+		runtime.GC()
+
+		after = runtime.NumGoroutine()
+		if after <= before+2 || time.Now().After(deadline) {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if after > before+2 {
+		t.Errorf("goroutine count grew from %d to %d after Close on abandoned readers; dispatcher/workers may be leaking", before, after)
+	}
+}
@@ -0,0 +1,61 @@
+package hbs
+
+import (
+	"compress/flate"
+	"crypto/aes"
+	"crypto/md5" //nolint:gosec
+	"fmt"
+	"io"
+
+	"github.com/nlippke/hbsdecipher-go/pkg/openssl"
+)
+
+// HeaderV1SaltSize is the size, in bytes, of the per-file salt embedded in
+// a QNAP cipher type 1 header.
+const HeaderV1SaltSize int = 8
+
+// newV1Reader handles the legacy QNAP cipher type 1 container
+// (QNAPFilePrefixV1Bytes). r is positioned right after the 8-byte magic.
+// The header that follows is a per-file salt plus a single byte flagging
+// whether the plaintext is additionally flate-compressed, the same
+// encoding v2 uses for its compressOptions[1] flag.
+func newV1Reader(r io.Reader, password string) (*Reader, error) {
+	salt := make([]byte, HeaderV1SaltSize)
+
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecipher, err)
+	}
+
+	compressFlag := make([]byte, 1)
+	if _, err := io.ReadFull(r, compressFlag); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecipher, err)
+	}
+
+	compressed := compressFlag[0] == 1
+
+	digest := md5.New()
+	keyAndIV := openssl.EVPBytesToKey(AesKeyStrength/8, 16, digest, salt, []byte(password), Iterations)
+
+	block, err := aes.NewCipher(keyAndIV[IndexKey])
+	if err != nil {
+		return nil, err
+	}
+
+	body := newBlockReader(r, block, keyAndIV[IndexIv])
+
+	var plain io.Reader = body
+
+	var closer io.Closer
+
+	if compressed {
+		fr := flate.NewReader(body)
+		plain = fr
+		closer = fr
+	}
+
+	return &Reader{
+		header: &Header{Version: 1, Compressed: compressed},
+		plain:  plain,
+		closer: closer,
+	}, nil
+}
@@ -0,0 +1,180 @@
+package hbs
+
+import (
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EncOpts configures NewWriter.
+type EncOpts struct {
+	// Size is the plaintext size in bytes that will be written. It is
+	// recorded in the header exactly as a genuine QNAP HBS v2 blob would,
+	// so that NewReader can later detect a truncated restore via its
+	// Header().Size. Callers must know it up front (e.g. via os.Stat)
+	// because, unlike the body, the header is written before any
+	// plaintext bytes arrive.
+	Size uint64
+	// Compress flate-compresses the plaintext before encryption, matching
+	// the compressOptions[1]==1 flag NewReader already understands.
+	Compress bool
+}
+
+// Writer streams plaintext into an AES-CBC/PKCS5 encrypted QNAP HBS v2
+// container, optionally flate-compressing it first.
+type Writer struct {
+	body   io.Writer
+	closer io.Closer
+	cbc    *cbcWriter
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.body.Write(p)
+}
+
+// Close flushes any pending compressed data, pads and encrypts the final
+// block, and writes it out. It does not close the underlying io.Writer
+// passed to NewWriter; that remains the caller's responsibility.
+func (w *Writer) Close() error {
+	var err error
+
+	if w.closer != nil {
+		err = w.closer.Close()
+	}
+
+	if cerr := w.cbc.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// NewWriter writes a QNAP HBS v2 header to w, deriving the header key from
+// password the same way decipherV2Header does, and returns a Writer that
+// encrypts (and, if opts.Compress is set, flate-compresses) everything
+// subsequently written to it.
+func NewWriter(w io.Writer, password string, opts EncOpts) (io.WriteCloser, error) {
+	ckey := make([]byte, 32)
+	salt := make([]byte, 16)
+
+	if _, err := rand.Read(ckey); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecipher, err)
+	}
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecipher, err)
+	}
+
+	if _, err := w.Write(QNAPFilePrefixV2Bytes); err != nil {
+		return nil, err
+	}
+
+	compressed := byte(0)
+	if opts.Compress {
+		compressed = 1
+	}
+
+	if _, err := w.Write([]byte{0, compressed}); err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(make([]byte, 6)); err != nil {
+		return nil, err
+	}
+
+	// Struct is : magic [8] + ckey[32] + salt [16] + size [8]
+	headerPlain := make([]byte, 64)
+	copy(headerPlain[0:8], QNAPFilePrefixV2Bytes)
+	copy(headerPlain[8:40], ckey)
+	copy(headerPlain[40:56], salt)
+	binary.BigEndian.PutUint64(headerPlain[56:64], opts.Size)
+
+	iter := 1 + 32/len(password)
+	passwordFinal := []byte(strings.Repeat(password, iter)[0:32])
+
+	hblock, err := aes.NewCipher(passwordFinal)
+	if err != nil {
+		return nil, err
+	}
+
+	headerCipher := make([]byte, 64)
+	for i := 0; i < 4; i++ {
+		hblock.Encrypt(headerCipher[i*16:(i+1)*16], headerPlain[i*16:(i+1)*16])
+	}
+
+	if _, err := w.Write(headerCipher); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(ckey)
+	if err != nil {
+		return nil, err
+	}
+
+	cbc := newCBCWriter(w, block, salt)
+
+	if opts.Compress {
+		fw, err := flate.NewWriter(cbc, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Writer{body: fw, closer: fw, cbc: cbc}, nil
+	}
+
+	return &Writer{body: cbc, cbc: cbc}, nil
+}
+
+// cbcWriter encrypts plaintext written to it with AES/CBC/PKCS5Padding,
+// buffering the trailing partial block until Close so it can be padded.
+type cbcWriter struct {
+	dst   io.Writer
+	mode  cipher.BlockMode
+	block cipher.Block
+	buf   []byte
+}
+
+func newCBCWriter(dst io.Writer, block cipher.Block, iv []byte) *cbcWriter {
+	return &cbcWriter{
+		dst:   dst,
+		mode:  cipher.NewCBCEncrypter(block, iv),
+		block: block,
+	}
+}
+
+func (c *cbcWriter) Write(p []byte) (int, error) {
+	c.buf = append(c.buf, p...)
+
+	n := (len(c.buf) / BlockSize) * BlockSize
+	if n > 0 {
+		encrypted := make([]byte, n)
+		c.mode.CryptBlocks(encrypted, c.buf[:n])
+
+		if _, err := c.dst.Write(encrypted); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrDecipher, err)
+		}
+
+		c.buf = append([]byte(nil), c.buf[n:]...)
+	}
+
+	return len(p), nil
+}
+
+// Close pads and encrypts the final, possibly partial, block.
+func (c *cbcWriter) Close() error {
+	padded := pkcs5Pad(c.buf, c.block.BlockSize())
+	encrypted := make([]byte, len(padded))
+	c.mode.CryptBlocks(encrypted, padded)
+
+	if _, err := c.dst.Write(encrypted); err != nil {
+		return fmt.Errorf("%w: %v", ErrDecipher, err)
+	}
+
+	return nil
+}
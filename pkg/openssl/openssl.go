@@ -1,7 +1,14 @@
-package main
+// Package openssl implements the bits of OpenSSL's "enc" command line tool
+// that hbsdecipher-go needs to interoperate with HBS blobs wrapped in the
+// classic "Salted__" container: key/IV derivation and the container prefix.
+package openssl
 
 import "hash"
 
+// Prefix is the 8-byte magic OpenSSL writes at the start of a "Salted__"
+// container, immediately followed by an 8-byte salt.
+var Prefix = []byte{'S', 'a', 'l', 't', 'e', 'd', '_', '_'}
+
 /*
  * EVPBytesToKey converts info to EVP BytesToKey format used by OpenSSL.
  * Thanks go to Ola Bini for releasing this source on his blog. The source was